@@ -0,0 +1,333 @@
+// Package checkpoint implements crash-safe, incremental checkpointing of a
+// shuffledns run, backed by bbolt. Long massdns runs over millions of
+// subdomains otherwise lose all progress on OOM, process restarts, or
+// wildcard-phase crashes.
+package checkpoint
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"strconv"
+	"sync"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	ipBucket      = []byte("ip")
+	recordsBucket = []byte("records")
+	metaBucket    = []byte("meta")
+	offsetKey     = []byte("offset")
+	outputKey     = []byte("outputPath")
+	wildcardsKey  = []byte("wildcardIPs")
+)
+
+// batchSize is the number of buffered ip/offset updates accumulated before
+// they're flushed to disk in a single transaction. Committing (and fsyncing)
+// a transaction per line would otherwise dominate runtime on the 10M+ line
+// inputs this feature targets.
+const batchSize = 1000
+
+// IPEntry mirrors store.IPMeta for persistence, without making this
+// package depend on the resolution pipeline.
+type IPEntry struct {
+	Hostnames []string
+	Counter   int
+}
+
+// RecordEntry mirrors store.RecordMeta for persistence, without making
+// this package depend on the resolution pipeline.
+type RecordEntry struct {
+	Host   string
+	Type   string
+	Answer string
+}
+
+// Store is an on-disk checkpoint of a run's progress.
+type Store struct {
+	db *bolt.DB
+
+	mutex          sync.Mutex
+	pendingIPs     map[string]IPEntry
+	pendingRecords []RecordEntry
+	pendingOffset  int64
+	offsetDirty    bool
+	dirty          int
+}
+
+// Open creates or reopens a checkpoint database at path.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0644, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{ipBucket, recordsBucket, metaBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db, pendingIPs: make(map[string]IPEntry)}, nil
+}
+
+// AddHostname buffers hostname against ip's checkpointed entry, appending
+// to whatever's already buffered for ip instead of requiring the caller to
+// re-derive and pass the full hostname list on every call (which turns
+// O(1) per-line work into O(k) for k hostnames already seen on a high
+// fan-in ip). It flushes to disk once batchSize updates have accumulated,
+// merging with whatever's already persisted for ip so hostnames buffered
+// in an earlier flush aren't overwritten.
+func (s *Store) AddHostname(ip, hostname string, counter int) error {
+	s.mutex.Lock()
+	entry := s.pendingIPs[ip]
+	entry.Hostnames = append(entry.Hostnames, hostname)
+	entry.Counter = counter
+	s.pendingIPs[ip] = entry
+	s.dirty++
+	needsFlush := s.dirty >= batchSize
+	s.mutex.Unlock()
+
+	if needsFlush {
+		return s.Flush()
+	}
+	return nil
+}
+
+// PutRecord buffers a non-address DNS record (AAAA, CNAME, NS, MX, TXT,
+// PTR, ...) for host, flushing to disk once batchSize updates have
+// accumulated. Unlike ip entries, records aren't merged by key, so every
+// call appends rather than overwrites.
+func (s *Store) PutRecord(host, recordType, answer string) error {
+	s.mutex.Lock()
+	s.pendingRecords = append(s.pendingRecords, RecordEntry{Host: host, Type: recordType, Answer: answer})
+	s.dirty++
+	needsFlush := s.dirty >= batchSize
+	s.mutex.Unlock()
+
+	if needsFlush {
+		return s.Flush()
+	}
+	return nil
+}
+
+// LoadRecords returns every non-address record checkpointed so far.
+func (s *Store) LoadRecords() ([]RecordEntry, error) {
+	var entries []RecordEntry
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(recordsBucket).ForEach(func(k, v []byte) error {
+			var entry RecordEntry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+			return nil
+		})
+	})
+
+	return entries, err
+}
+
+// LoadIPs returns every ip checkpointed so far, keyed by ip.
+func (s *Store) LoadIPs() (map[string]IPEntry, error) {
+	entries := make(map[string]IPEntry)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(ipBucket).ForEach(func(k, v []byte) error {
+			var entry IPEntry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				return err
+			}
+			entries[string(k)] = entry
+			return nil
+		})
+	})
+
+	return entries, err
+}
+
+// SetOffset buffers the last massdns output byte offset that has been
+// fully parsed, flushing to disk once batchSize updates have accumulated,
+// so a resumed run can seek straight past it.
+func (s *Store) SetOffset(offset int64) error {
+	s.mutex.Lock()
+	s.pendingOffset = offset
+	s.offsetDirty = true
+	s.dirty++
+	needsFlush := s.dirty >= batchSize
+	s.mutex.Unlock()
+
+	if needsFlush {
+		return s.Flush()
+	}
+	return nil
+}
+
+// Offset returns the last checkpointed byte offset, or 0 if none exists.
+func (s *Store) Offset() (int64, error) {
+	var offset int64
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(metaBucket).Get(offsetKey)
+		if data == nil {
+			return nil
+		}
+
+		parsed, err := strconv.ParseInt(string(data), 10, 64)
+		if err != nil {
+			return err
+		}
+		offset = parsed
+		return nil
+	})
+
+	return offset, err
+}
+
+// Flush persists any buffered ip and offset updates in a single
+// transaction. It's called automatically every batchSize updates, and must
+// also be called before relying on the checkpoint being up to date on disk
+// (Close does this).
+func (s *Store) Flush() error {
+	s.mutex.Lock()
+	ips := s.pendingIPs
+	records := s.pendingRecords
+	offset := s.pendingOffset
+	offsetDirty := s.offsetDirty
+	s.pendingIPs = make(map[string]IPEntry)
+	s.pendingRecords = nil
+	s.offsetDirty = false
+	s.dirty = 0
+	s.mutex.Unlock()
+
+	if len(ips) == 0 && len(records) == 0 && !offsetDirty {
+		return nil
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(ipBucket)
+		for ip, entry := range ips {
+			// Merge with whatever was already persisted for ip, since
+			// entry only holds the hostnames buffered since the last flush.
+			if existing := bucket.Get([]byte(ip)); existing != nil {
+				var prev IPEntry
+				if err := json.Unmarshal(existing, &prev); err != nil {
+					return err
+				}
+				entry.Hostnames = append(prev.Hostnames, entry.Hostnames...)
+			}
+
+			data, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(ip), data); err != nil {
+				return err
+			}
+		}
+
+		recBucket := tx.Bucket(recordsBucket)
+		for _, entry := range records {
+			data, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+			seq, err := recBucket.NextSequence()
+			if err != nil {
+				return err
+			}
+			if err := recBucket.Put(itob(seq), data); err != nil {
+				return err
+			}
+		}
+
+		if offsetDirty {
+			meta := tx.Bucket(metaBucket)
+			if err := meta.Put(offsetKey, []byte(strconv.FormatInt(offset, 10))); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// itob encodes seq as a big-endian key, so records sort in the insertion
+// order NextSequence handed them out in.
+func itob(seq uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, seq)
+	return b
+}
+
+// SetOutputPath persists the path of the massdns output file the offset is
+// being measured against, so a resumed run parses the same file the
+// checkpointed offset refers to instead of a freshly generated one.
+func (s *Store) SetOutputPath(path string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(metaBucket).Put(outputKey, []byte(path))
+	})
+}
+
+// OutputPath returns the checkpointed massdns output file path, or "" if
+// none has been recorded yet.
+func (s *Store) OutputPath() (string, error) {
+	var path string
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(metaBucket).Get(outputKey)
+		if data == nil {
+			return nil
+		}
+		path = string(data)
+		return nil
+	})
+
+	return path, err
+}
+
+// SetWildcardIPs persists the set of ips already confirmed as wildcards,
+// so the expensive probing that found them isn't redone on resume.
+func (s *Store) SetWildcardIPs(ips []string) error {
+	data, err := json.Marshal(ips)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(metaBucket).Put(wildcardsKey, data)
+	})
+}
+
+// WildcardIPs returns the set of ips already confirmed as wildcards.
+func (s *Store) WildcardIPs() ([]string, error) {
+	var ips []string
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(metaBucket).Get(wildcardsKey)
+		if data == nil {
+			return nil
+		}
+		return json.Unmarshal(data, &ips)
+	})
+
+	return ips, err
+}
+
+// Close flushes any buffered updates and releases the underlying database
+// file.
+func (s *Store) Close() error {
+	if err := s.Flush(); err != nil {
+		s.db.Close()
+		return err
+	}
+	return s.db.Close()
+}
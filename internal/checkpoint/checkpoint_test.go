@@ -0,0 +1,142 @@
+package checkpoint
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOffsetRoundTrip(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "checkpoint.db"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	offset, err := store.Offset()
+	require.NoError(t, err)
+	require.Zero(t, offset)
+
+	require.NoError(t, store.SetOffset(1024))
+
+	// Not flushed to disk yet: still buffered in memory below batchSize.
+	offset, err = store.Offset()
+	require.NoError(t, err)
+	require.Zero(t, offset)
+
+	require.NoError(t, store.Flush())
+
+	offset, err = store.Offset()
+	require.NoError(t, err)
+	require.EqualValues(t, 1024, offset)
+}
+
+func TestSetOffsetAutoFlushesAtBatchSize(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "checkpoint.db"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	for i := 0; i < batchSize-1; i++ {
+		require.NoError(t, store.SetOffset(int64(i)))
+	}
+	offset, err := store.Offset()
+	require.NoError(t, err)
+	require.Zero(t, offset)
+
+	// The batchSize-th update crosses the threshold and flushes.
+	require.NoError(t, store.SetOffset(int64(batchSize)))
+	offset, err = store.Offset()
+	require.NoError(t, err)
+	require.EqualValues(t, batchSize, offset)
+}
+
+func TestAddHostnameBufferedUntilFlush(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "checkpoint.db"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	require.NoError(t, store.AddHostname("1.2.3.4", "sub.example.com", 1))
+
+	ips, err := store.LoadIPs()
+	require.NoError(t, err)
+	require.Empty(t, ips)
+
+	require.NoError(t, store.Flush())
+
+	ips, err = store.LoadIPs()
+	require.NoError(t, err)
+	require.Equal(t, IPEntry{Hostnames: []string{"sub.example.com"}, Counter: 1}, ips["1.2.3.4"])
+}
+
+// TestAddHostnameMergesAcrossFlushes covers the case the old PutIP-with-a-
+// full-hostname-slice approach couldn't: a high fan-in ip accumulating
+// hostnames across more than one flush must keep every hostname seen, not
+// just the ones buffered since the last flush.
+func TestAddHostnameMergesAcrossFlushes(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "checkpoint.db"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	require.NoError(t, store.AddHostname("1.2.3.4", "one.example.com", 1))
+	require.NoError(t, store.Flush())
+	require.NoError(t, store.AddHostname("1.2.3.4", "two.example.com", 2))
+	require.NoError(t, store.Flush())
+
+	ips, err := store.LoadIPs()
+	require.NoError(t, err)
+	require.Equal(t, IPEntry{Hostnames: []string{"one.example.com", "two.example.com"}, Counter: 2}, ips["1.2.3.4"])
+}
+
+func TestPutRecordBufferedUntilFlush(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "checkpoint.db"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	require.NoError(t, store.PutRecord("sub.example.com", "CNAME", "edge.example.net"))
+
+	records, err := store.LoadRecords()
+	require.NoError(t, err)
+	require.Empty(t, records)
+
+	require.NoError(t, store.PutRecord("sub.example.com", "TXT", "v=spf1 ~all"))
+	require.NoError(t, store.Flush())
+
+	records, err = store.LoadRecords()
+	require.NoError(t, err)
+	require.Equal(t, []RecordEntry{
+		{Host: "sub.example.com", Type: "CNAME", Answer: "edge.example.net"},
+		{Host: "sub.example.com", Type: "TXT", Answer: "v=spf1 ~all"},
+	}, records)
+}
+
+func TestCloseFlushesPendingUpdates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.db")
+	store, err := Open(path)
+	require.NoError(t, err)
+
+	require.NoError(t, store.SetOffset(42))
+	require.NoError(t, store.Close())
+
+	reopened, err := Open(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	offset, err := reopened.Offset()
+	require.NoError(t, err)
+	require.EqualValues(t, 42, offset)
+}
+
+func TestOutputPathRoundTrip(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "checkpoint.db"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	path, err := store.OutputPath()
+	require.NoError(t, err)
+	require.Empty(t, path)
+
+	require.NoError(t, store.SetOutputPath("/tmp/run1/massdns-output"))
+
+	path, err = store.OutputPath()
+	require.NoError(t, err)
+	require.Equal(t, "/tmp/run1/massdns-output", path)
+}
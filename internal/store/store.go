@@ -0,0 +1,192 @@
+// Package store implements an in-memory store for deduplicating discovered
+// IP addresses, their hostnames and any other DNS records found during a run.
+package store
+
+import "sync"
+
+// IPMeta contains metadata about a discovered IP address, namely the
+// hostnames that resolved to it and how many times a hostname was recorded
+// against it (a hostname seen again counts again).
+type IPMeta struct {
+	IP        string
+	Hostnames map[string]struct{}
+	Counter   int
+}
+
+// RecordMeta holds a single non-address DNS answer (CNAME, NS, MX, TXT,
+// PTR, ...) discovered for a host, preserved alongside its record type
+// since it can't be deduplicated by IP the way address records are.
+type RecordMeta struct {
+	Host   string
+	Type   string
+	Answer string
+}
+
+// hostMeta holds side information about how a host was resolved, used to
+// enrich streaming output without needing to thread it through every caller.
+type hostMeta struct {
+	Resolver string
+	Rcode    string
+}
+
+// Store holds the deduplicated IP and record metadata discovered during a run.
+type Store struct {
+	mutex sync.Mutex
+
+	IP      map[string]*IPMeta
+	Records []RecordMeta
+
+	hostMeta map[string]hostMeta
+}
+
+// New creates a new store instance.
+func New() *Store {
+	return &Store{IP: make(map[string]*IPMeta), hostMeta: make(map[string]hostMeta)}
+}
+
+// Exists checks if an ip has already been recorded in the store.
+func (s *Store) Exists(ip string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, ok := s.IP[ip]
+	return ok
+}
+
+// Get returns the metadata for an ip previously added to the store.
+func (s *Store) Get(ip string) *IPMeta {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.IP[ip]
+}
+
+// AddHostname records hostname as resolving to ip, creating ip's entry if
+// this is the first hostname seen for it, and returns ip's Counter
+// afterwards, which grows by one on every call, even for a hostname already
+// recorded. The whole check-then-act sequence runs under a single
+// lock so concurrent callers sharing the same ip (e.g. native mode's
+// per-host goroutines) never race on its Hostnames map.
+func (s *Store) AddHostname(ip, hostname string) (counter int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	record, ok := s.IP[ip]
+	if !ok {
+		s.IP[ip] = &IPMeta{IP: ip, Hostnames: map[string]struct{}{hostname: {}}, Counter: 1}
+		return 1
+	}
+	record.Hostnames[hostname] = struct{}{}
+	record.Counter++
+	return record.Counter
+}
+
+// Import restores a previously checkpointed ip record as-is, bypassing the
+// usual AddHostname path, for use when resuming a run.
+func (s *Store) Import(ip string, hostnames []string, counter int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	hostSet := make(map[string]struct{}, len(hostnames))
+	for _, hostname := range hostnames {
+		hostSet[hostname] = struct{}{}
+	}
+	s.IP[ip] = &IPMeta{IP: ip, Hostnames: hostSet, Counter: counter}
+}
+
+// Delete removes an ip and its associated hostnames from the store.
+func (s *Store) Delete(ip string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.IP, ip)
+}
+
+// AddRecord stores a non-address DNS answer (CNAME, NS, MX, TXT, PTR, ...)
+// discovered for host, preserving its record type for later output.
+func (s *Store) AddRecord(host, recordType, answer string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.Records = append(s.Records, RecordMeta{Host: host, Type: recordType, Answer: answer})
+}
+
+// CNAMEsForHost returns the CNAME answers recorded for host, if any.
+func (s *Store) CNAMEsForHost(host string) []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var cnames []string
+	for _, record := range s.Records {
+		if record.Host == host && record.Type == "CNAME" {
+			cnames = append(cnames, record.Answer)
+		}
+	}
+	return cnames
+}
+
+// RecordsForHost returns every non-address record (AAAA, CNAME, NS, MX,
+// TXT, PTR, ...) discovered for host, if any.
+func (s *Store) RecordsForHost(host string) []RecordMeta {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var records []RecordMeta
+	for _, record := range s.Records {
+		if record.Host == host {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// RecordOnlyHosts returns the hosts that have a non-address record but no
+// entry in IP at all, e.g. a host whose only answer was an MX or NS record
+// rather than an A. These would otherwise never be surfaced by anything
+// that only walks IP.
+func (s *Store) RecordOnlyHosts() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	withIP := make(map[string]struct{})
+	for _, record := range s.IP {
+		for host := range record.Hostnames {
+			withIP[host] = struct{}{}
+		}
+	}
+
+	seen := make(map[string]struct{})
+	var hosts []string
+	for _, record := range s.Records {
+		if _, ok := withIP[record.Host]; ok {
+			continue
+		}
+		if _, ok := seen[record.Host]; ok {
+			continue
+		}
+		seen[record.Host] = struct{}{}
+		hosts = append(hosts, record.Host)
+	}
+	return hosts
+}
+
+// SetHostMeta records which resolver answered for host and with what
+// rcode. Only the native resolver path has this information available.
+func (s *Store) SetHostMeta(host, resolver, rcode string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.hostMeta[host] = hostMeta{Resolver: resolver, Rcode: rcode}
+}
+
+// HostMeta returns the resolver and rcode recorded for host, if any.
+func (s *Store) HostMeta(host string) (resolver, rcode string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	meta := s.hostMeta[host]
+	return meta.Resolver, meta.Rcode
+}
+
+// Close releases any resources held by the store.
+func (s *Store) Close() {}
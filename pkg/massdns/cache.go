@@ -0,0 +1,76 @@
+package massdns
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+)
+
+// wildcardCache is the on-disk representation of the wildcard ip and CNAME
+// maps, so that long-running campaigns don't have to re-probe the same
+// wildcard anchors on every run.
+type wildcardCache struct {
+	IPs    []string `json:"ips"`
+	CNAMEs []string `json:"cnames"`
+}
+
+// loadWildcardCache restores previously discovered wildcard ips and CNAME
+// anchors from config.CacheFile, if it exists.
+func (c *Client) loadWildcardCache() error {
+	data, err := ioutil.ReadFile(c.config.CacheFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var cache wildcardCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return err
+	}
+
+	c.wildcardIPMutex.Lock()
+	for _, ip := range cache.IPs {
+		c.wildcardIPMap[ip] = struct{}{}
+	}
+	c.wildcardIPMutex.Unlock()
+
+	c.wildcardCNAMEMutex.Lock()
+	for _, cname := range cache.CNAMEs {
+		c.wildcardCNAMEMap[cname] = struct{}{}
+	}
+	c.wildcardCNAMEMutex.Unlock()
+
+	return nil
+}
+
+// saveWildcardCache persists the wildcard ip and CNAME maps discovered
+// during this run to config.CacheFile. It's a no-op if no cache file was
+// configured.
+func (c *Client) saveWildcardCache() error {
+	if c.config.CacheFile == "" {
+		return nil
+	}
+
+	c.wildcardIPMutex.Lock()
+	ips := make([]string, 0, len(c.wildcardIPMap))
+	for ip := range c.wildcardIPMap {
+		ips = append(ips, ip)
+	}
+	c.wildcardIPMutex.Unlock()
+
+	c.wildcardCNAMEMutex.Lock()
+	cnames := make([]string, 0, len(c.wildcardCNAMEMap))
+	for cname := range c.wildcardCNAMEMap {
+		cnames = append(cnames, cname)
+	}
+	c.wildcardCNAMEMutex.Unlock()
+
+	data, err := json.Marshal(wildcardCache{IPs: ips, CNAMEs: cnames})
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(c.config.CacheFile, data, 0644)
+}
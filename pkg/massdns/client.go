@@ -0,0 +1,73 @@
+package massdns
+
+import (
+	"sync"
+
+	"github.com/projectdiscovery/shuffledns/internal/checkpoint"
+	"github.com/projectdiscovery/shuffledns/pkg/wildcards"
+)
+
+// Config contains the configuration options for running shuffledns.
+type Config struct {
+	InputFile        string
+	MassdnsRaw       string
+	ResolversFile    string
+	MassdnsPath      string
+	OutputFile       string
+	OutputFormat     string
+	TempDir          string
+	Domain           string
+	CacheFile        string
+	PcapFile         string
+	Interface        string
+	Resume           string
+	Mode             string
+	QueryTypes       []string
+	Threads          int
+	WildcardsThreads int
+	Retries          int
+	AllowRoot        bool
+	Json             bool
+	StrictWildcard   bool
+	NoFilterWildcard bool
+}
+
+// Client is a client for running the enumeration process.
+type Client struct {
+	config Config
+
+	wildcardResolver *wildcards.Resolver
+
+	wildcardIPMutex sync.Mutex
+	wildcardIPMap   map[string]struct{}
+
+	wildcardCNAMEMutex sync.Mutex
+	wildcardCNAMEMap   map[string]struct{}
+
+	checkpoint *checkpoint.Store
+}
+
+// New creates a new client for running the enumeration process.
+func New(config Config) (*Client, error) {
+	client := &Client{
+		config:           config,
+		wildcardIPMap:    make(map[string]struct{}),
+		wildcardCNAMEMap: make(map[string]struct{}),
+	}
+
+	if config.Domain != "" {
+		resolvers, err := readLines(config.ResolversFile)
+		if err != nil {
+			return nil, err
+		}
+		client.wildcardResolver = wildcards.New(config.Domain, resolvers)
+	}
+
+	if config.CacheFile != "" {
+		if err := client.loadWildcardCache(); err != nil {
+			return nil, err
+		}
+	}
+
+	return client, nil
+}
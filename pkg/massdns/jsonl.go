@@ -0,0 +1,88 @@
+package massdns
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/projectdiscovery/gologger"
+)
+
+// jsonlRecord is a single line of streaming JSONL output.
+type jsonlRecord struct {
+	Host      string   `json:"host"`
+	A         []string `json:"a,omitempty"`
+	AAAA      []string `json:"aaaa,omitempty"`
+	CNAME     []string `json:"cname,omitempty"`
+	NS        []string `json:"ns,omitempty"`
+	MX        []string `json:"mx,omitempty"`
+	TXT       []string `json:"txt,omitempty"`
+	PTR       []string `json:"ptr,omitempty"`
+	Resolver  string   `json:"resolver,omitempty"`
+	Rcode     string   `json:"rcode,omitempty"`
+	Timestamp string   `json:"timestamp"`
+}
+
+// jsonlWriter flushes one confirmed, non-wildcard record at a time so that
+// downstream tools (dnsx, httpx, nuclei, ...) can consume results line by
+// line without waiting for the whole scan to complete.
+type jsonlWriter struct {
+	mutex  sync.Mutex
+	file   *os.File
+	writer *bufio.Writer
+}
+
+// newJSONLWriter creates a streaming writer. If path is empty, records are
+// only printed to stdout.
+func newJSONLWriter(path string) (*jsonlWriter, error) {
+	w := &jsonlWriter{}
+	if path == "" {
+		return w, nil
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w.file = file
+	w.writer = bufio.NewWriter(file)
+	return w, nil
+}
+
+// Write marshals rec and flushes it immediately.
+func (w *jsonlWriter) Write(rec jsonlRecord) error {
+	rec.Timestamp = time.Now().Format(time.RFC3339)
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.writer != nil {
+		if _, err := w.writer.Write(line); err != nil {
+			return err
+		}
+		if _, err := w.writer.WriteString("\n"); err != nil {
+			return err
+		}
+		if err := w.writer.Flush(); err != nil {
+			return err
+		}
+	}
+	gologger.Silent().Msgf("%s\n", line)
+	return nil
+}
+
+// Close closes the backing output file, if any. Write has already flushed
+// every record, so nothing is left buffered.
+func (w *jsonlWriter) Close() error {
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Close()
+}
@@ -0,0 +1,35 @@
+package massdns
+
+import (
+	"testing"
+
+	"github.com/projectdiscovery/shuffledns/internal/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildJSONLRecord(t *testing.T) {
+	st := store.New()
+	st.AddRecord("sub.example.com", "CNAME", "edge.example.net")
+	st.AddRecord("sub.example.com", "TXT", "v=spf1 ~all")
+	st.SetHostMeta("sub.example.com", "8.8.8.8", "NOERROR")
+
+	rec := buildJSONLRecord(st, "sub.example.com", []string{"1.2.3.4"})
+
+	require.Equal(t, "sub.example.com", rec.Host)
+	require.Equal(t, []string{"1.2.3.4"}, rec.A)
+	require.Equal(t, []string{"edge.example.net"}, rec.CNAME)
+	require.Equal(t, []string{"v=spf1 ~all"}, rec.TXT)
+	require.Equal(t, "8.8.8.8", rec.Resolver)
+	require.Equal(t, "NOERROR", rec.Rcode)
+	require.Empty(t, rec.AAAA)
+}
+
+func TestBuildJSONLRecordHostWithNoA(t *testing.T) {
+	st := store.New()
+	st.AddRecord("mail.example.com", "MX", "mx1.example.com")
+
+	rec := buildJSONLRecord(st, "mail.example.com", nil)
+
+	require.Nil(t, rec.A)
+	require.Equal(t, []string{"mx1.example.com"}, rec.MX)
+}
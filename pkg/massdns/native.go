@@ -0,0 +1,149 @@
+package massdns
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/shuffledns/internal/store"
+	"github.com/remeh/sizedwaitgroup"
+)
+
+// nativeQueryTimeout is the per-query timeout used by the native resolver.
+const nativeQueryTimeout = 5 * time.Second
+
+// runNative performs bulk resolution in-process using github.com/miekg/dns,
+// bypassing the massdns binary entirely. It is used when config.Mode is set
+// to "native" and streams resolved records directly into the store instead
+// of staging an intermediate massdns output file.
+func (c *Client) runNative(st *store.Store) error {
+	resolvers, err := readLines(c.config.ResolversFile)
+	if err != nil {
+		return fmt.Errorf("could not read resolvers file: %w", err)
+	}
+	if len(resolvers) == 0 {
+		return fmt.Errorf("no resolvers found in %s", c.config.ResolversFile)
+	}
+
+	hosts, err := readLines(c.config.InputFile)
+	if err != nil {
+		return fmt.Errorf("could not read input file: %w", err)
+	}
+
+	gologger.Info().Msgf("Resolving %d hosts in native mode\n", len(hosts))
+	now := time.Now()
+
+	udpClient := &dns.Client{Net: "udp", Timeout: nativeQueryTimeout}
+	tcpClient := &dns.Client{Net: "tcp", Timeout: nativeQueryTimeout}
+
+	var resolverIndex uint64
+	wg := sizedwaitgroup.New(c.config.Threads)
+
+	for _, host := range hosts {
+		wg.Add()
+		go func(host string) {
+			defer wg.Done()
+
+			resolver := resolvers[atomic.AddUint64(&resolverIndex, 1)%uint64(len(resolvers))]
+			c.resolveNative(udpClient, tcpClient, resolver, host, st)
+		}(host)
+	}
+	wg.Wait()
+
+	gologger.Info().Msgf("Native resolution took %s\n", time.Since(now))
+	return nil
+}
+
+// resolveNative resolves a single host against resolver for every
+// configured query type, retrying on failure and falling back to TCP when
+// the UDP response comes back truncated, then stores the answers found.
+func (c *Client) resolveNative(udpClient, tcpClient *dns.Client, resolver, host string, st *store.Store) {
+	address := net.JoinHostPort(resolver, "53")
+
+	for _, queryType := range c.queryTypes() {
+		rrType, ok := dns.StringToType[strings.ToUpper(queryType)]
+		if !ok {
+			gologger.Warning().Msgf("Skipping unknown query type: %s\n", queryType)
+			continue
+		}
+
+		msg := new(dns.Msg)
+		msg.SetQuestion(dns.Fqdn(host), rrType)
+		msg.RecursionDesired = true
+
+		var resp *dns.Msg
+		var err error
+
+		for attempt := 0; attempt <= c.config.Retries; attempt++ {
+			resp, _, err = udpClient.ExchangeContext(context.Background(), msg, address)
+			if err == nil && resp != nil && resp.Truncated {
+				resp, _, err = tcpClient.ExchangeContext(context.Background(), msg, address)
+			}
+			if err == nil {
+				break
+			}
+		}
+		if err != nil || resp == nil || resp.Rcode != dns.RcodeSuccess {
+			continue
+		}
+
+		st.SetHostMeta(host, resolver, dns.RcodeToString[resp.Rcode])
+
+		for _, rr := range resp.Answer {
+			storeAnswer(st, host, rr)
+		}
+	}
+}
+
+// storeAnswer records a single resource record against the store, keeping
+// A records in the deduplicated ip map and everything else as a typed record.
+func storeAnswer(st *store.Store, host string, rr dns.RR) {
+	if a, ok := rr.(*dns.A); ok {
+		st.AddHostname(a.A.String(), host)
+		return
+	}
+
+	recordType := dns.TypeToString[rr.Header().Rrtype]
+
+	switch answer := rr.(type) {
+	case *dns.AAAA:
+		st.AddRecord(host, recordType, answer.AAAA.String())
+	case *dns.CNAME:
+		st.AddRecord(host, recordType, answer.Target)
+	case *dns.NS:
+		st.AddRecord(host, recordType, answer.Ns)
+	case *dns.MX:
+		st.AddRecord(host, recordType, answer.Mx)
+	case *dns.TXT:
+		st.AddRecord(host, recordType, strings.Join(answer.Txt, " "))
+	case *dns.PTR:
+		st.AddRecord(host, recordType, answer.Ptr)
+	}
+}
+
+// readLines reads a file into a slice of its non-blank, trimmed lines.
+func readLines(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
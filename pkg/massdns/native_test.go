@@ -0,0 +1,53 @@
+package massdns
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/projectdiscovery/shuffledns/internal/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreAnswer(t *testing.T) {
+	st := store.New()
+
+	storeAnswer(st, "sub.example.com", &dns.A{A: net.ParseIP("1.2.3.4")})
+	storeAnswer(st, "sub.example.com", &dns.AAAA{Hdr: dns.RR_Header{Rrtype: dns.TypeAAAA}, AAAA: net.ParseIP("::1")})
+	storeAnswer(st, "sub.example.com", &dns.CNAME{Hdr: dns.RR_Header{Rrtype: dns.TypeCNAME}, Target: "edge.example.net."})
+	storeAnswer(st, "sub.example.com", &dns.MX{Hdr: dns.RR_Header{Rrtype: dns.TypeMX}, Mx: "mx1.example.com."})
+	storeAnswer(st, "sub.example.com", &dns.TXT{Hdr: dns.RR_Header{Rrtype: dns.TypeTXT}, Txt: []string{"v=spf1", "~all"}})
+
+	require.True(t, st.Exists("1.2.3.4"))
+	record := st.Get("1.2.3.4")
+	require.Contains(t, record.Hostnames, "sub.example.com")
+
+	records := st.RecordsForHost("sub.example.com")
+	require.Len(t, records, 4)
+}
+
+// TestStoreAnswerConcurrentSameIP exercises many goroutines resolving
+// different hosts to the same ip concurrently, the pattern that used to
+// race on IPMeta.Hostnames (a plain map) because the old check-then-act
+// sequence in storeAnswer wasn't atomic. Run with -race to catch a
+// regression.
+func TestStoreAnswerConcurrentSameIP(t *testing.T) {
+	st := store.New()
+
+	const workers = 200
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			storeAnswer(st, fmt.Sprintf("host%d.example.com", i), &dns.A{A: net.ParseIP("9.9.9.9")})
+		}(i)
+	}
+	wg.Wait()
+
+	record := st.Get("9.9.9.9")
+	require.Equal(t, workers, record.Counter)
+	require.Len(t, record.Hostnames, workers)
+}
@@ -0,0 +1,112 @@
+package massdns
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/projectdiscovery/shuffledns/internal/store"
+)
+
+// runPcap ingests DNS responses from a pcap file (or, if config.Interface
+// is set and config.PcapFile is not, a live capture) instead of generating
+// traffic of its own. This lets operators reuse the wildcard-filtering,
+// dedup and output pipeline on captures collected elsewhere, e.g. from a
+// sniffer sat on an authoritative or recursive resolver.
+//
+// Reading an offline file only needs gopacket/pcapgo, a pure Go
+// implementation, so this (the default, primary case) doesn't require cgo
+// or libpcap to be installed. Live interface capture does need libpcap and
+// is only available in binaries built with the "pcap" tag; see
+// pcap_live.go and pcap_nolive.go.
+func (c *Client) runPcap(st *store.Store) error {
+	source, linkType, closeSource, err := openPcapSource(c.config.PcapFile, c.config.Interface)
+	if err != nil {
+		return fmt.Errorf("could not open pcap source: %w", err)
+	}
+	defer closeSource()
+
+	allowedTypes := make(map[string]struct{})
+	for _, queryType := range c.queryTypes() {
+		allowedTypes[strings.ToUpper(queryType)] = struct{}{}
+	}
+
+	packetSource := gopacket.NewPacketSource(source, linkType)
+	for packet := range packetSource.Packets() {
+		dnsLayer := packet.Layer(layers.LayerTypeDNS)
+		if dnsLayer == nil {
+			continue
+		}
+
+		dnsPacket, ok := dnsLayer.(*layers.DNS)
+		if !ok || !dnsPacket.QR {
+			// Only interested in responses, not queries.
+			continue
+		}
+
+		storeDNSAnswers(st, dnsPacket, allowedTypes)
+	}
+
+	return nil
+}
+
+// storeDNSAnswers records every answer of an allowed type carried by
+// dnsPacket against the store.
+func storeDNSAnswers(st *store.Store, dnsPacket *layers.DNS, allowedTypes map[string]struct{}) {
+	for _, answer := range dnsPacket.Answers {
+		recordType := answer.Type.String()
+		if _, ok := allowedTypes[recordType]; !ok {
+			continue
+		}
+
+		domain := strings.TrimSuffix(string(answer.Name), ".")
+
+		// answer.Data is the raw, still-wire-encoded RDATA (length-prefixed
+		// labels, possibly name-compression pointers), so every decodable
+		// type must use gopacket's already-decoded field instead, mirroring
+		// native.go's storeAnswer.
+		switch recordType {
+		case "A":
+			st.AddHostname(answer.IP.String(), domain)
+		case "AAAA":
+			st.AddRecord(domain, recordType, answer.IP.String())
+		case "CNAME":
+			st.AddRecord(domain, recordType, strings.TrimSuffix(string(answer.CNAME), "."))
+		case "NS":
+			st.AddRecord(domain, recordType, strings.TrimSuffix(string(answer.NS), "."))
+		case "PTR":
+			st.AddRecord(domain, recordType, strings.TrimSuffix(string(answer.PTR), "."))
+		case "MX":
+			st.AddRecord(domain, recordType, strings.TrimSuffix(string(answer.MX.Name), "."))
+		case "TXT":
+			txts := make([]string, len(answer.TXTs))
+			for i, txt := range answer.TXTs {
+				txts[i] = string(txt)
+			}
+			st.AddRecord(domain, recordType, strings.Join(txts, " "))
+		}
+	}
+}
+
+// openPcapSource opens pcapFile for offline reading with the pure Go
+// pcapgo reader. iface is only consulted when pcapFile is empty, and
+// delegates to openLiveCapture, which requires a binary built with the
+// "pcap" tag.
+func openPcapSource(pcapFile, iface string) (gopacket.PacketDataSource, layers.LinkType, func() error, error) {
+	if pcapFile != "" {
+		f, err := os.Open(pcapFile)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		reader, err := pcapgo.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, 0, nil, err
+		}
+		return reader, reader.LinkType(), f.Close, nil
+	}
+	return openLiveCapture(iface)
+}
@@ -0,0 +1,21 @@
+//go:build pcap
+
+package massdns
+
+import (
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+)
+
+// openLiveCapture opens iface for live packet capture via libpcap. It's
+// only built into binaries compiled with `-tags pcap`, since it cgo-binds
+// to libpcap and would otherwise force every shuffledns build (and
+// platform) to need libpcap dev headers just to read an offline pcap file.
+func openLiveCapture(iface string) (gopacket.PacketDataSource, layers.LinkType, func() error, error) {
+	handle, err := pcap.OpenLive(iface, 65535, true, pcap.BlockForever)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	return handle, handle.LinkType(), func() error { handle.Close(); return nil }, nil
+}
@@ -0,0 +1,18 @@
+//go:build !pcap
+
+package massdns
+
+import (
+	"fmt"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// openLiveCapture is the stub used by default builds, which don't link
+// libpcap. Rebuild with `-tags pcap` (and libpcap dev headers installed)
+// to capture from a live interface; reading an offline pcap file with
+// --pcap-file doesn't need either.
+func openLiveCapture(iface string) (gopacket.PacketDataSource, layers.LinkType, func() error, error) {
+	return nil, 0, nil, fmt.Errorf("live interface capture requires a build with the \"pcap\" tag (and libpcap installed); use --pcap-file to read an offline capture instead")
+}
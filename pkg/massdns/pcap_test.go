@@ -0,0 +1,56 @@
+package massdns
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket/layers"
+	"github.com/projectdiscovery/shuffledns/internal/store"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStoreDNSAnswersDecodesRecords locks in the fix from 69b0cdd: answers
+// must be read from gopacket's decoded fields (CNAME/NS/PTR/MX.Name/TXTs),
+// not the raw wire RDATA in answer.Data.
+func TestStoreDNSAnswersDecodesRecords(t *testing.T) {
+	st := store.New()
+	allowed := map[string]struct{}{"A": {}, "CNAME": {}, "MX": {}, "TXT": {}}
+
+	dnsPacket := &layers.DNS{
+		QR: true,
+		Answers: []layers.DNSResourceRecord{
+			{Name: []byte("sub.example.com."), Type: layers.DNSTypeA, IP: net.ParseIP("1.2.3.4")},
+			{Name: []byte("sub.example.com."), Type: layers.DNSTypeCNAME, CNAME: []byte("edge.example.net.")},
+			{Name: []byte("sub.example.com."), Type: layers.DNSTypeMX, MX: layers.DNSMX{Name: []byte("mx1.example.com.")}},
+			{Name: []byte("sub.example.com."), Type: layers.DNSTypeTXT, TXTs: [][]byte{[]byte("v=spf1"), []byte("~all")}},
+			// Not in allowedTypes, should be skipped entirely.
+			{Name: []byte("sub.example.com."), Type: layers.DNSTypeNS, NS: []byte("ns1.example.com.")},
+		},
+	}
+
+	storeDNSAnswers(st, dnsPacket, allowed)
+
+	require.True(t, st.Exists("1.2.3.4"))
+	record := st.Get("1.2.3.4")
+	require.Contains(t, record.Hostnames, "sub.example.com")
+
+	var gotCNAME, gotMX, gotTXT bool
+	for _, record := range st.RecordsForHost("sub.example.com") {
+		switch record.Type {
+		case "CNAME":
+			require.Equal(t, "edge.example.net", record.Answer)
+			gotCNAME = true
+		case "MX":
+			require.Equal(t, "mx1.example.com", record.Answer)
+			gotMX = true
+		case "TXT":
+			require.Equal(t, "v=spf1 ~all", record.Answer)
+			gotTXT = true
+		case "NS":
+			t.Fatalf("NS record should have been filtered out by allowedTypes")
+		}
+	}
+	require.True(t, gotCNAME)
+	require.True(t, gotMX)
+	require.True(t, gotTXT)
+}
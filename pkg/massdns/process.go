@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -14,12 +15,28 @@ import (
 	"time"
 
 	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/shuffledns/internal/checkpoint"
 	"github.com/projectdiscovery/shuffledns/internal/store"
 	"github.com/projectdiscovery/shuffledns/pkg/parser"
 	"github.com/remeh/sizedwaitgroup"
 	"github.com/rs/xid"
 )
 
+// checkpointFile is the name of the bbolt database a run's progress is
+// checkpointed to, stored alongside its temp/resume directory.
+const checkpointFile = "checkpoint.db"
+
+// Resume continues a previous run whose checkpoint was written to dir,
+// restoring its discovered ips, already-confirmed wildcards, and the
+// massdns output byte offset it had reached, so the expensive work already
+// done isn't repeated. Only the massdns path checkpoints ips, records and
+// the offset; native and pcap runs resolve or ingest again from the start,
+// and only their confirmed wildcard ips are restored.
+func (c *Client) Resume(dir string) error {
+	c.config.Resume = dir
+	return c.Process()
+}
+
 // Process runs the actual enumeration process returning a file
 func (c *Client) Process() error {
 	// Process a created list or the massdns input
@@ -41,42 +58,124 @@ func (c *Client) Process() error {
 	shstore := store.New()
 	defer shstore.Close()
 
-	// Set the correct target file
-	massDNSOutput := filepath.Join(c.config.TempDir, xid.New().String())
-	if c.config.MassdnsRaw != "" {
-		massDNSOutput = c.config.MassdnsRaw
+	// Checkpoint progress incrementally so a crash or OOM on a run over
+	// millions of subdomains doesn't lose everything. --resume points this
+	// at a previous run's directory instead of the fresh one for this run.
+	checkpointDir := c.config.TempDir
+	if c.config.Resume != "" {
+		checkpointDir = c.config.Resume
+	}
+	cp, err := checkpoint.Open(filepath.Join(checkpointDir, checkpointFile))
+	if err != nil {
+		return fmt.Errorf("could not open checkpoint: %w", err)
+	}
+	c.checkpoint = cp
+	defer c.checkpoint.Close()
+
+	if c.config.Resume != "" {
+		if err := c.resumeFromCheckpoint(shstore); err != nil {
+			return fmt.Errorf("could not resume from checkpoint: %w", err)
+		}
 	}
 
-	// Check if we need to run massdns
-	if c.config.MassdnsRaw == "" {
-		// Create a temporary file for the massdns output
-		gologger.Info().Msgf("Creating temporary massdns output file: %s\n", massDNSOutput)
-		err = c.runMassDNS(massDNSOutput, shstore)
+	// In jsonl mode, records are streamed out as soon as they're confirmed
+	// non-wildcard instead of waiting for the whole run to finish.
+	var jsonl *jsonlWriter
+	if c.config.OutputFormat == "jsonl" {
+		jsonl, err = newJSONLWriter(c.config.OutputFile)
 		if err != nil {
-			return fmt.Errorf("could not execute massdns: %w", err)
+			return fmt.Errorf("could not create jsonl writer: %w", err)
 		}
+		defer jsonl.Close()
 	}
 
-	gologger.Info().Msgf("Started parsing massdns output\n")
+	// A pcap source is a passive alternative to generating traffic: it
+	// skips resolution entirely and ingests already-captured DNS
+	// responses, so it takes priority over every other mode.
+	if c.config.PcapFile != "" || c.config.Interface != "" {
+		gologger.Info().Msgf("Ingesting DNS responses from pcap\n")
+		err = c.runPcap(shstore)
+		if err != nil {
+			return fmt.Errorf("could not ingest pcap: %w", err)
+		}
+	} else if c.config.Mode == "native" {
+		// The native mode resolves hosts in-process with github.com/miekg/dns
+		// and streams results straight into the store, so it skips the
+		// massdns binary and the intermediate output file entirely.
+		gologger.Info().Msgf("Resolving in native mode\n")
+		err = c.runNative(shstore)
+		if err != nil {
+			return fmt.Errorf("could not resolve in native mode: %w", err)
+		}
+	} else {
+		// Set the correct target file
+		massDNSOutput := filepath.Join(c.config.TempDir, xid.New().String())
+		if c.config.MassdnsRaw != "" {
+			massDNSOutput = c.config.MassdnsRaw
+		}
 
-	err = c.parseMassDNSOutput(massDNSOutput, shstore)
-	if err != nil {
-		return fmt.Errorf("could not parse massdns output: %w", err)
-	}
+		needsMassDNS := c.config.MassdnsRaw == ""
+
+		// The checkpointed offset was measured against a specific file, so
+		// a resumed run must keep parsing that same file instead of a
+		// freshly generated one, or the offset silently misaligns.
+		if c.config.MassdnsRaw == "" && c.config.Resume != "" {
+			prevOutput, err := c.checkpoint.OutputPath()
+			if err != nil {
+				return fmt.Errorf("could not read checkpointed output path: %w", err)
+			}
+			if prevOutput != "" {
+				massDNSOutput = prevOutput
+				needsMassDNS = false
+			}
+		}
+
+		// Check if we need to run massdns
+		if needsMassDNS {
+			// Create a temporary file for the massdns output
+			gologger.Info().Msgf("Creating temporary massdns output file: %s\n", massDNSOutput)
+			err = c.runMassDNS(massDNSOutput, shstore)
+			if err != nil {
+				return fmt.Errorf("could not execute massdns: %w", err)
+			}
+		}
 
-	gologger.Info().Msgf("Massdns output parsing compeleted\n")
+		if c.config.MassdnsRaw == "" {
+			if err := c.checkpoint.SetOutputPath(massDNSOutput); err != nil {
+				return fmt.Errorf("could not checkpoint output path: %w", err)
+			}
+		}
+
+		gologger.Info().Msgf("Started parsing massdns output\n")
+
+		err = c.parseMassDNSOutput(massDNSOutput, shstore)
+		if err != nil {
+			return fmt.Errorf("could not parse massdns output: %w", err)
+		}
+
+		gologger.Info().Msgf("Massdns output parsing compeleted\n")
+	}
 	// Perform wildcard filtering only if domain name has been specified and --no-filter-wildcard is not set
 	if !c.config.NoFilterWildcard && c.config.Domain != "" {
 		gologger.Info().Msgf("Started removing wildcards records\n")
-		err = c.filterWildcards(shstore)
+		err = c.filterWildcards(shstore, jsonl)
 		if err != nil {
 			return fmt.Errorf("could not parse massdns output: %w", err)
 		}
 		gologger.Info().Msgf("Wildcard removal completed\n")
+	} else if jsonl != nil {
+		// Nothing is going to clear records as non-wildcard for us, so
+		// every record already found is considered confirmed.
+		c.flushJSONL(shstore, jsonl)
 	}
 
 	gologger.Info().Msgf("Finished enumeration, started writing output\n")
 
+	// jsonl output has already been streamed out record by record.
+	if jsonl != nil {
+		return nil
+	}
+
 	// Write the final elaborated list out
 	return c.writeOutput(shstore)
 }
@@ -88,7 +187,11 @@ func (c *Client) runMassDNS(output string, store *store.Store) error {
 		gologger.Info().Msgf("Executing massdns\n")
 	}
 	now := time.Now()
-	args := []string{"-r", c.config.ResolversFile, "-o", "Snl", "-t", "A", c.config.InputFile, "-w", output, "-s", strconv.Itoa(c.config.Threads)}
+	args := []string{"-r", c.config.ResolversFile, "-o", "Snl"}
+	for _, queryType := range c.queryTypes() {
+		args = append(args, "-t", queryType)
+	}
+	args = append(args, c.config.InputFile, "-w", output, "-s", strconv.Itoa(c.config.Threads))
 	if c.config.AllowRoot {
 		args = append(args, "--root")
 	}
@@ -104,40 +207,105 @@ func (c *Client) runMassDNS(output string, store *store.Store) error {
 	return nil
 }
 
-func (c *Client) parseMassDNSOutput(output string, store *store.Store) error {
+// resumeFromCheckpoint restores ips and wildcard state persisted by a
+// previous run so neither resolution nor wildcard probing is repeated.
+func (c *Client) resumeFromCheckpoint(st *store.Store) error {
+	ips, err := c.checkpoint.LoadIPs()
+	if err != nil {
+		return fmt.Errorf("could not load checkpointed ips: %w", err)
+	}
+	for ip, entry := range ips {
+		st.Import(ip, entry.Hostnames, entry.Counter)
+	}
+	gologger.Info().Msgf("Restored %d ips from checkpoint\n", len(ips))
+
+	records, err := c.checkpoint.LoadRecords()
+	if err != nil {
+		return fmt.Errorf("could not load checkpointed records: %w", err)
+	}
+	for _, record := range records {
+		st.AddRecord(record.Host, record.Type, record.Answer)
+	}
+	gologger.Info().Msgf("Restored %d records from checkpoint\n", len(records))
+
+	wildcardIPs, err := c.checkpoint.WildcardIPs()
+	if err != nil {
+		return fmt.Errorf("could not load checkpointed wildcard ips: %w", err)
+	}
+	c.wildcardIPMutex.Lock()
+	for _, ip := range wildcardIPs {
+		c.wildcardIPMap[ip] = struct{}{}
+	}
+	c.wildcardIPMutex.Unlock()
+
+	return nil
+}
+
+func (c *Client) parseMassDNSOutput(output string, st *store.Store) error {
 	massdnsOutput, err := os.Open(output)
 	if err != nil {
 		return fmt.Errorf("could not open massdns output file: %w", err)
 	}
 	defer massdnsOutput.Close()
 
-	// at first we need the full structure in memory to elaborate it in parallell
-	err = parser.Parse(massdnsOutput, func(domain string, ip []string) {
-		for _, ip := range ip {
-			// Check if ip exists in the store. If not,
-			// add the ip to the map and continue with the next ip.
-			if !store.Exists(ip) {
-				store.New(ip, domain)
-				continue
+	var offset int64
+	if c.config.Resume != "" {
+		offset, err = c.checkpoint.Offset()
+		if err != nil {
+			return fmt.Errorf("could not read checkpoint offset: %w", err)
+		}
+		if offset > 0 {
+			gologger.Info().Msgf("Resuming parse of %s from offset %d\n", output, offset)
+			if _, err := massdnsOutput.Seek(offset, io.SeekStart); err != nil {
+				return fmt.Errorf("could not seek to checkpoint offset: %w", err)
 			}
+		}
+	}
+
+	reader := bufio.NewReaderSize(massdnsOutput, 1024*1024)
+	for {
+		line, readErr := reader.ReadString('\n')
+		offset += int64(len(line))
 
-			// Get the IP meta-information from the store.
-			record := store.Get(ip)
+		if domain, recordType, answer, ok := parser.ParseLine(line); ok {
+			c.storeRecord(st, domain, recordType, answer)
 
-			// Put the new hostname and increment the counter by 1.
-			record.Hostnames[domain] = struct{}{}
-			record.Counter++
+			if err := c.checkpoint.SetOffset(offset); err != nil {
+				return fmt.Errorf("could not checkpoint offset: %w", err)
+			}
 		}
-	})
 
-	if err != nil {
-		return fmt.Errorf("could not parse massdns output: %w", err)
+		if readErr != nil {
+			if readErr == io.EOF {
+				break
+			}
+			return fmt.Errorf("could not read massdns output: %w", readErr)
+		}
 	}
 
 	return nil
 }
 
-func (c *Client) filterWildcards(st *store.Store) error {
+// storeRecord applies a single parsed (domain, type, answer) line to the
+// store, deduplicating A records by ip as before and keeping everything
+// else as a typed record.
+func (c *Client) storeRecord(st *store.Store, domain, recordType, answer string) {
+	if recordType != "A" {
+		st.AddRecord(domain, recordType, answer)
+		if err := c.checkpoint.PutRecord(domain, recordType, answer); err != nil {
+			gologger.Warning().Msgf("Could not checkpoint %s record for %s: %s\n", recordType, domain, err)
+		}
+		return
+	}
+
+	ip := answer
+	counter := st.AddHostname(ip, domain)
+	if err := c.checkpoint.AddHostname(ip, domain, counter); err != nil {
+		gologger.Warning().Msgf("Could not checkpoint %s: %s\n", ip, err)
+	}
+}
+
+func (c *Client) filterWildcards(st *store.Store, jsonl *jsonlWriter) error {
 	// Start to work in parallel on wildcards
 	wildcardWg := sizedwaitgroup.New(c.config.WildcardsThreads)
 
@@ -157,6 +325,8 @@ func (c *Client) filterWildcards(st *store.Store) error {
 			go func(record *store.IPMeta) {
 				defer wildcardWg.Done()
 
+				isRecordWildcard := false
+
 				for host := range record.Hostnames {
 					isWildcard, ips := c.wildcardResolver.LookupHost(host)
 					if len(ips) > 0 {
@@ -173,23 +343,166 @@ func (c *Client) filterWildcards(st *store.Store) error {
 						// we also mark the original ip as wildcard, since at least once it resolved to this host
 						c.wildcardIPMap[record.IP] = struct{}{}
 						c.wildcardIPMutex.Unlock()
+						isRecordWildcard = true
 						break
 					}
+
+					// Some wildcards rotate ips behind a shared CNAME target
+					// (e.g. a load balancer) rather than resolving directly,
+					// so a host whose CNAME chain terminates in a known
+					// wildcard anchor is flagged too, even if its current
+					// ip set doesn't overlap with wildcardIPMap.
+					if cname := c.wildcardResolver.LookupCNAME(host); cname != "" {
+						anchor := c.wildcardResolver.ProbeAnchor(parentZone(host, c.config.Domain))
+
+						c.wildcardCNAMEMutex.Lock()
+						_, knownCNAME := c.wildcardCNAMEMap[cname]
+						c.wildcardCNAMEMutex.Unlock()
+
+						if knownCNAME || (anchor != "" && cname == anchor) {
+							c.wildcardCNAMEMutex.Lock()
+							c.wildcardCNAMEMap[cname] = struct{}{}
+							c.wildcardCNAMEMutex.Unlock()
+
+							c.wildcardIPMutex.Lock()
+							c.wildcardIPMap[record.IP] = struct{}{}
+							c.wildcardIPMutex.Unlock()
+							isRecordWildcard = true
+							break
+						}
+					}
+				}
+
+				if !isRecordWildcard && jsonl != nil {
+					c.flushRecordJSONL(st, record, jsonl)
 				}
 			}(record)
+		} else if jsonl != nil {
+			// Below the wildcard threshold, so it's already confirmed clean.
+			c.flushRecordJSONL(st, record, jsonl)
 		}
 	}
 
 	wildcardWg.Wait()
 
+	// Hosts with no A record have no ip to check against the wildcard map,
+	// so they're never touched by the loop above and can be flushed as-is.
+	if jsonl != nil {
+		c.flushRecordOnlyHostsJSONL(st, jsonl)
+	}
+
 	// drop all wildcard from the store
 	for wildcardIP := range c.wildcardIPMap {
 		st.Delete(wildcardIP)
 	}
 
+	if err := c.saveWildcardCache(); err != nil {
+		return fmt.Errorf("could not save wildcard cache: %w", err)
+	}
+
+	c.wildcardIPMutex.Lock()
+	wildcardIPs := make([]string, 0, len(c.wildcardIPMap))
+	for ip := range c.wildcardIPMap {
+		wildcardIPs = append(wildcardIPs, ip)
+	}
+	c.wildcardIPMutex.Unlock()
+	if err := c.checkpoint.SetWildcardIPs(wildcardIPs); err != nil {
+		return fmt.Errorf("could not checkpoint wildcard ips: %w", err)
+	}
+
 	return nil
 }
 
+// flushRecordJSONL streams one jsonl line per hostname held by record,
+// enriched with its A address and every other record type gathered for it.
+func (c *Client) flushRecordJSONL(st *store.Store, record *store.IPMeta, jsonl *jsonlWriter) {
+	for host := range record.Hostnames {
+		c.flushHostJSONL(st, host, []string{record.IP}, jsonl)
+	}
+}
+
+// flushJSONL streams every record currently in the store, used when
+// wildcard filtering is disabled and nothing else will clear them.
+func (c *Client) flushJSONL(st *store.Store, jsonl *jsonlWriter) {
+	for _, record := range st.IP {
+		c.flushRecordJSONL(st, record, jsonl)
+	}
+	c.flushRecordOnlyHostsJSONL(st, jsonl)
+}
+
+// flushRecordOnlyHostsJSONL streams hosts that only ever produced
+// non-address records (NS/MX/TXT/PTR, or AAAA for a query type that found
+// no A record), which otherwise have no entry in store.IP and would never
+// be flushed by anything that only walks it.
+func (c *Client) flushRecordOnlyHostsJSONL(st *store.Store, jsonl *jsonlWriter) {
+	for _, host := range st.RecordOnlyHosts() {
+		c.flushHostJSONL(st, host, nil, jsonl)
+	}
+}
+
+// flushHostJSONL writes a single jsonl line for host, combining its
+// resolved A addresses (if any) with every other record type found for it
+// and its resolver metadata.
+func (c *Client) flushHostJSONL(st *store.Store, host string, a []string, jsonl *jsonlWriter) {
+	rec := buildJSONLRecord(st, host, a)
+	if err := jsonl.Write(rec); err != nil {
+		gologger.Warning().Msgf("Could not write jsonl record for %s: %s\n", host, err)
+	}
+}
+
+// buildJSONLRecord shapes the jsonl line for host, combining its resolved A
+// addresses (if any) with every other record type found for it and its
+// resolver metadata.
+func buildJSONLRecord(st *store.Store, host string, a []string) jsonlRecord {
+	resolver, rcode := st.HostMeta(host)
+	rec := jsonlRecord{
+		Host:     host,
+		A:        a,
+		Resolver: resolver,
+		Rcode:    rcode,
+	}
+	for _, record := range st.RecordsForHost(host) {
+		switch record.Type {
+		case "AAAA":
+			rec.AAAA = append(rec.AAAA, record.Answer)
+		case "CNAME":
+			rec.CNAME = append(rec.CNAME, record.Answer)
+		case "NS":
+			rec.NS = append(rec.NS, record.Answer)
+		case "MX":
+			rec.MX = append(rec.MX, record.Answer)
+		case "TXT":
+			rec.TXT = append(rec.TXT, record.Answer)
+		case "PTR":
+			rec.PTR = append(rec.PTR, record.Answer)
+		}
+	}
+	return rec
+}
+
+// parentZone returns the immediate parent zone of host, i.e. host with its
+// leftmost label stripped, falling back to domain once host has been
+// reduced to it.
+func parentZone(host, domain string) string {
+	host = strings.TrimSuffix(host, ".")
+	domain = strings.TrimSuffix(domain, ".")
+
+	if host == domain {
+		return domain
+	}
+
+	idx := strings.Index(host, ".")
+	if idx == -1 {
+		return domain
+	}
+
+	parent := host[idx+1:]
+	if len(parent) < len(domain) {
+		return domain
+	}
+	return parent
+}
+
 func (c *Client) writeOutput(store *store.Store) error {
 	// Write the unique deduplicated output to the file or stdout
 	// depending on what the user has asked.
@@ -239,6 +552,28 @@ func (c *Client) writeOutput(store *store.Store) error {
 		}
 	}
 
+	// Emit the non-address records (AAAA, CNAME, NS, MX, TXT, PTR, ...)
+	// gathered alongside the address ones. These only make sense as
+	// structured output, so they're only written in JSON mode.
+	if c.config.Json {
+		for _, record := range store.Records {
+			recordJson, err := json.Marshal(map[string]interface{}{
+				"host":   record.Host,
+				"type":   record.Type,
+				"answer": record.Answer,
+			})
+			if err != nil {
+				return fmt.Errorf("could not marshal record as json: %v", err)
+			}
+
+			data := string(recordJson) + "\n"
+			if output != nil {
+				_, _ = w.WriteString(data)
+			}
+			gologger.Silent().Msgf("%s", data)
+		}
+	}
+
 	// Close the files and return
 	if output != nil {
 		w.Flush()
@@ -246,3 +581,12 @@ func (c *Client) writeOutput(store *store.Store) error {
 	}
 	return nil
 }
+
+// queryTypes returns the DNS record types to resolve, defaulting to A
+// when config.QueryTypes is unset.
+func (c *Client) queryTypes() []string {
+	if len(c.config.QueryTypes) == 0 {
+		return []string{"A"}
+	}
+	return c.config.QueryTypes
+}
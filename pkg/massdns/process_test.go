@@ -0,0 +1,50 @@
+package massdns
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryTypes(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want []string
+	}{
+		{name: "defaults to A when unset", cfg: Config{}, want: []string{"A"}},
+		{
+			name: "returns the configured types as-is",
+			cfg:  Config{QueryTypes: []string{"A", "AAAA", "MX"}},
+			want: []string{"A", "AAAA", "MX"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{config: tt.cfg}
+			require.Equal(t, tt.want, c.queryTypes())
+		})
+	}
+}
+
+func TestParentZone(t *testing.T) {
+	tests := []struct {
+		name   string
+		host   string
+		domain string
+		want   string
+	}{
+		{name: "strips one label", host: "www.example.com", domain: "example.com", want: "example.com"},
+		{name: "strips only the leftmost of multiple labels", host: "a.b.example.com", domain: "example.com", want: "b.example.com"},
+		{name: "host already equals domain", host: "example.com", domain: "example.com", want: "example.com"},
+		{name: "no further labels falls back to domain", host: "com", domain: "example.com", want: "example.com"},
+		{name: "trailing dots are ignored", host: "www.example.com.", domain: "example.com.", want: "example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, parentZone(tt.host, tt.domain))
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// Package parser implements a parser for massdns "Snl" simple-list output.
+package parser
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// Parse reads massdns output produced with the "Snl" output format and
+// invokes callback once per domain with the accumulated list of resolved
+// IPv4 addresses. Non-A records are ignored.
+func Parse(reader io.Reader, callback func(domain string, ip []string)) error {
+	ips := make(map[string][]string)
+
+	err := ParseRecords(reader, func(domain, recordType, answer string) {
+		if recordType != "A" {
+			return
+		}
+		ips[domain] = append(ips[domain], answer)
+	})
+	if err != nil {
+		return err
+	}
+
+	for domain, addrs := range ips {
+		callback(domain, addrs)
+	}
+	return nil
+}
+
+// ParseRecords reads massdns output produced with the "Snl" output format
+// and invokes callback for every well-formed line, regardless of record
+// type. Each line has the form "domain. TYPE answer"; blank and malformed
+// lines are skipped.
+func ParseRecords(reader io.Reader, callback func(domain, recordType, answer string)) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+
+	for scanner.Scan() {
+		domain, recordType, answer, ok := ParseLine(scanner.Text())
+		if !ok {
+			continue
+		}
+		callback(domain, recordType, answer)
+	}
+	return scanner.Err()
+}
+
+// ParseLine parses a single line of massdns "Snl" output ("domain. TYPE
+// answer") into its components. ok is false for blank or malformed lines.
+func ParseLine(line string) (domain, recordType, answer string, ok bool) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return "", "", "", false
+	}
+
+	parts := strings.Fields(line)
+	if len(parts) < 3 {
+		return "", "", "", false
+	}
+	return parts[0], parts[1], strings.Join(parts[2:], " "), true
+}
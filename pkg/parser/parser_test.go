@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name       string
+		line       string
+		wantDomain string
+		wantType   string
+		wantAnswer string
+		wantOk     bool
+	}{
+		{
+			name:       "valid A record",
+			line:       "sub.example.com. A 1.2.3.4",
+			wantDomain: "sub.example.com.",
+			wantType:   "A",
+			wantAnswer: "1.2.3.4",
+			wantOk:     true,
+		},
+		{
+			name:       "answer with spaces is rejoined",
+			line:       "sub.example.com. TXT v=spf1 include:_spf.example.com ~all",
+			wantDomain: "sub.example.com.",
+			wantType:   "TXT",
+			wantAnswer: "v=spf1 include:_spf.example.com ~all",
+			wantOk:     true,
+		},
+		{
+			name:       "trims surrounding whitespace",
+			line:       "  sub.example.com. CNAME target.example.com.  \n",
+			wantDomain: "sub.example.com.",
+			wantType:   "CNAME",
+			wantAnswer: "target.example.com.",
+			wantOk:     true,
+		},
+		{
+			name:   "blank line",
+			line:   "",
+			wantOk: false,
+		},
+		{
+			name:   "whitespace-only line",
+			line:   "   \t  ",
+			wantOk: false,
+		},
+		{
+			name:   "missing answer field",
+			line:   "sub.example.com. A",
+			wantOk: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domain, recordType, answer, ok := ParseLine(tt.line)
+			require.Equal(t, tt.wantOk, ok)
+			if !tt.wantOk {
+				return
+			}
+			require.Equal(t, tt.wantDomain, domain)
+			require.Equal(t, tt.wantType, recordType)
+			require.Equal(t, tt.wantAnswer, answer)
+		})
+	}
+}
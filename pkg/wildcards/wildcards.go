@@ -0,0 +1,168 @@
+// Package wildcards implements wildcard DNS detection, including following
+// CNAME chains so that wildcards fronted by a shared load balancer (e.g.
+// *.example.com -> lb.provider.net -> rotating ips) are still caught.
+package wildcards
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	labelLength  = 24
+	labelCharset = "abcdefghijklmnopqrstuvwxyz0123456789"
+	queryTimeout = 5 * time.Second
+	// maxCNAMEDepth bounds how many CNAME hops LookupCNAME follows.
+	maxCNAMEDepth = 10
+)
+
+// Resolver performs wildcard detection for a root domain by probing
+// random, virtually-guaranteed-nonexistent labels and comparing their
+// resolution against candidate hosts.
+type Resolver struct {
+	domain    string
+	resolvers []string
+	client    *dns.Client
+	index     uint64
+
+	mutex  sync.Mutex
+	anchor map[string]string // parent zone -> terminal CNAME anchor discovered for it
+}
+
+// New creates a wildcard resolver for domain using the given resolvers.
+func New(domain string, resolvers []string) *Resolver {
+	return &Resolver{
+		domain:    strings.TrimSuffix(domain, "."),
+		resolvers: resolvers,
+		client:    &dns.Client{Net: "udp", Timeout: queryTimeout},
+		anchor:    make(map[string]string),
+	}
+}
+
+// LookupHost resolves host and reports whether it looks like a wildcard
+// response, along with the ip addresses found so callers can blacklist them.
+func (r *Resolver) LookupHost(host string) (bool, map[string]struct{}) {
+	ips := make(map[string]struct{})
+
+	addrs := r.lookupA(host)
+	for _, ip := range addrs {
+		ips[ip] = struct{}{}
+	}
+
+	wildcardAddrs := r.lookupA(r.randomLabel() + "." + r.domain)
+
+	return sameAddressSet(addrs, wildcardAddrs), ips
+}
+
+// LookupCNAME returns the terminal target of host's CNAME chain, or an
+// empty string if host has no CNAME record. At most maxCNAMEDepth hops
+// are followed.
+func (r *Resolver) LookupCNAME(host string) string {
+	target := ""
+	for depth := 0; depth < maxCNAMEDepth; depth++ {
+		next := r.nextCNAME(host)
+		if next == "" {
+			break
+		}
+		target, host = next, next
+	}
+	return target
+}
+
+// nextCNAME returns the target of host's own CNAME record, if any.
+func (r *Resolver) nextCNAME(host string) string {
+	resp := r.query(host, dns.TypeCNAME)
+	if resp == nil {
+		return ""
+	}
+	for _, rr := range resp.Answer {
+		if cname, ok := rr.(*dns.CNAME); ok {
+			return strings.TrimSuffix(cname.Target, ".")
+		}
+	}
+	return ""
+}
+
+// ProbeAnchor probes a random nonexistent label under parent and returns
+// the terminal CNAME it resolves to, if any. The result is cached per
+// parent zone since probing is expensive and stable for the run's lifetime.
+func (r *Resolver) ProbeAnchor(parent string) string {
+	r.mutex.Lock()
+	if anchor, ok := r.anchor[parent]; ok {
+		r.mutex.Unlock()
+		return anchor
+	}
+	r.mutex.Unlock()
+
+	anchor := r.LookupCNAME(r.randomLabel() + "." + parent)
+
+	r.mutex.Lock()
+	r.anchor[parent] = anchor
+	r.mutex.Unlock()
+
+	return anchor
+}
+
+func (r *Resolver) lookupA(host string) []string {
+	resp := r.query(host, dns.TypeA)
+	if resp == nil {
+		return nil
+	}
+
+	var ips []string
+	for _, rr := range resp.Answer {
+		if a, ok := rr.(*dns.A); ok {
+			ips = append(ips, a.A.String())
+		}
+	}
+	return ips
+}
+
+func (r *Resolver) query(host string, qtype uint16) *dns.Msg {
+	if len(r.resolvers) == 0 {
+		return nil
+	}
+
+	resolver := r.resolvers[atomic.AddUint64(&r.index, 1)%uint64(len(r.resolvers))]
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(host), qtype)
+	msg.RecursionDesired = true
+
+	resp, _, err := r.client.ExchangeContext(context.Background(), msg, net.JoinHostPort(resolver, "53"))
+	if err != nil || resp == nil || resp.Rcode != dns.RcodeSuccess {
+		return nil
+	}
+	return resp
+}
+
+func (r *Resolver) randomLabel() string {
+	b := make([]byte, labelLength)
+	for i := range b {
+		b[i] = labelCharset[rand.Intn(len(labelCharset))]
+	}
+	return string(b)
+}
+
+func sameAddressSet(a, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return false
+	}
+	set := make(map[string]struct{}, len(b))
+	for _, ip := range b {
+		set[ip] = struct{}{}
+	}
+	for _, ip := range a {
+		if _, ok := set[ip]; !ok {
+			return false
+		}
+	}
+	return true
+}
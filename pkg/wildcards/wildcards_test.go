@@ -0,0 +1,59 @@
+package wildcards
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSameAddressSet(t *testing.T) {
+	tests := []struct {
+		name string
+		a    []string
+		b    []string
+		want bool
+	}{
+		{
+			name: "identical single ip",
+			a:    []string{"1.2.3.4"},
+			b:    []string{"1.2.3.4"},
+			want: true,
+		},
+		{
+			name: "a is a subset of b",
+			a:    []string{"1.2.3.4"},
+			b:    []string{"1.2.3.4", "5.6.7.8"},
+			want: true,
+		},
+		{
+			name: "disjoint ips",
+			a:    []string{"1.2.3.4"},
+			b:    []string{"5.6.7.8"},
+			want: false,
+		},
+		{
+			name: "a empty",
+			a:    nil,
+			b:    []string{"1.2.3.4"},
+			want: false,
+		},
+		{
+			name: "b empty",
+			a:    []string{"1.2.3.4"},
+			b:    nil,
+			want: false,
+		},
+		{
+			name: "both empty",
+			a:    nil,
+			b:    nil,
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, sameAddressSet(tt.a, tt.b))
+		})
+	}
+}